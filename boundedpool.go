@@ -0,0 +1,167 @@
+package bufpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// A BoundedPool is a Pool variant that caps the number of live shards it
+// will keep around, trading latency for a hard bound on memory. Unlike
+// Pool, which lets sync.Pool create as many shards as concurrent demand
+// requires, a BoundedPool gates shard creation behind maxShards and, once
+// that cap is reached, callers either block (MakeContext) or fail fast
+// (TryMake) waiting for a shard to be returned.
+type BoundedPool struct {
+	sz        int
+	maxShards int
+	free      chan *shard // channel-backed free list of idle shards
+	created   int64       // number of shards created so far, atomically updated
+	cfg       cfg
+}
+
+// NewBounded returns a new *BoundedPool where each shard has `shardSz`
+// bytes, and at most `maxShards` shards will ever be live at once. Of the
+// Option values defined in this package, only WithMaxRetries applies to
+// a BoundedPool (it bounds make's non-blocking retry loop); TTL/idle-count
+// reaping (WithShardTTL, WithMaxIdleShards) and WithClassOverheadFactor
+// are ClassPool/Pool-only and are accepted but silently ignored here,
+// since maxShards already gives a BoundedPool a hard cap on live shards.
+func NewBounded(shardSz, maxShards int, opts ...Option) *BoundedPool {
+	if maxShards <= 0 {
+		panic("maxShards should be greater than zero")
+	}
+	cfg := defaultCfg
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &BoundedPool{
+		sz:        shardSz,
+		maxShards: maxShards,
+		free:      make(chan *shard, maxShards),
+		cfg:       cfg,
+	}
+}
+
+// Make returns a Buffer which contains a []byte with length/capacity
+// equal to `n` bytes, blocking indefinitely for a shard to become
+// available if the pool is at capacity. See MakeContext to bound the
+// wait, or TryMake to fail fast instead of blocking.
+func (bp *BoundedPool) Make(n int) Buffer {
+	b, _ := bp.MakeContext(context.Background(), n)
+	return b
+}
+
+// MakeContext is like Make, but blocks waiting for a shard to be
+// returned to the pool when the cap is reached, until ctx is done. It
+// returns false if ctx expires before a shard became available.
+func (bp *BoundedPool) MakeContext(ctx context.Context, n int) (Buffer, bool) {
+	b, ok, _ := bp.make(ctx, n, true)
+	return b, ok
+}
+
+// TryMake is a non-blocking variant of Make: it returns false immediately
+// if no shard is available and the pool is already at capacity.
+func (bp *BoundedPool) TryMake(n int) (Buffer, bool) {
+	b, ok, _ := bp.make(context.Background(), n, false)
+	return b, ok
+}
+
+// pollBackoff bounds how long make spins between attempts when the only
+// idle shard it finds still has outstanding sub-buffers referencing it
+// (so it can't be reclaimed yet, but also isn't sitting idle elsewhere).
+const pollBackoff = 100 * time.Microsecond
+
+func (bp *BoundedPool) make(ctx context.Context, n int, block bool) (Buffer, bool, error) {
+	if n < 0 {
+		panic("size should be greater than zero")
+	}
+	if n == 0 || n >= bp.sz {
+		return mallocBuffer(n), true, nil
+	}
+	for attempt := 0; ; attempt++ {
+		st, ok, err := bp.getShard(ctx, block)
+		if err != nil || !ok {
+			return Buffer{}, false, err
+		}
+
+		var s *shard
+		var start int64
+		if off, ok := st.reserve(n); ok {
+			// Enough bytes left in this shard to satisfy the request.
+			s, start = st, off
+		} else if atomic.LoadInt64(&st.refs) == 0 {
+			// All old buffers returned; start again at the beginning.
+			s, start = st, st.resetAndReserve(n)
+		}
+		if s == nil {
+			// Doesn't fit and still has live sub-buffers: release it and
+			// try again rather than reusing it unsafely (see getShard).
+			bp.putShard(st)
+			if !block {
+				if attempt >= bp.cfg.maxRetries {
+					return Buffer{}, false, nil
+				}
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return Buffer{}, false, ctx.Err()
+			case <-time.After(pollBackoff):
+			}
+			continue
+		}
+
+		atomic.AddInt64(&s.refs, 1) // incr refcount
+		end := start + int64(n)
+		b := s.b[start:end:end]
+		bp.putShard(s) // release now that we're done manipulating shard state
+		debugTrackBuffer(b)
+		return Buffer{B: b, refs: &s.refs, shard: s}, true, nil
+	}
+}
+
+// getShard returns an idle shard from the free list, creating a new one
+// if the pool hasn't yet reached maxShards. If the pool is at capacity
+// and no shard is idle, it blocks until one is returned (if block is
+// true and ctx isn't done) or reports failure (if block is false).
+func (bp *BoundedPool) getShard(ctx context.Context, block bool) (*shard, bool, error) {
+	select {
+	case s := <-bp.free:
+		return s, true, nil
+	default:
+	}
+
+	if atomic.AddInt64(&bp.created, 1) <= int64(bp.maxShards) {
+		return &shard{b: make([]byte, bp.sz)}, true, nil
+	}
+	atomic.AddInt64(&bp.created, -1) // revert, we're already at capacity
+
+	if !block {
+		return nil, false, nil
+	}
+	select {
+	case s := <-bp.free:
+		return s, true, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+func (bp *BoundedPool) putShard(s *shard) {
+	bp.free <- s // never blocks: we just freed the capacity slot this shard occupies
+}
+
+// Stats describes a BoundedPool's current shard usage.
+type Stats struct {
+	InUse int
+	Idle  int
+}
+
+// Stats returns the number of shards currently checked out (InUse) and
+// sitting idle in the free list (Idle).
+func (bp *BoundedPool) Stats() Stats {
+	idle := len(bp.free)
+	created := int(atomic.LoadInt64(&bp.created))
+	return Stats{InUse: created - idle, Idle: idle}
+}
@@ -0,0 +1,90 @@
+package bufpool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jsign/bufpool"
+)
+
+func TestBoundedBasics(t *testing.T) {
+	pool := bufpool.NewBounded(100, 2)
+	buf := pool.Make(20)
+	if len(buf.B) != 20 || cap(buf.B) != 20 {
+		t.Fatalf("bad make len=%v cap=%v, want 20, 20", len(buf.B), cap(buf.B))
+	}
+	buf.Done()
+}
+
+func TestBoundedTryMakeFailsAtCapacity(t *testing.T) {
+	pool := bufpool.NewBounded(100, 1)
+
+	buf1, ok := pool.TryMake(20)
+	if !ok {
+		t.Fatal("expected first TryMake to succeed")
+	}
+
+	// The only shard doesn't have 90 bytes left, and the pool is already
+	// at its cap of 1 shard, so a second concurrent caller must fail fast
+	// rather than create another one.
+	if _, ok := pool.TryMake(90); ok {
+		t.Fatal("expected TryMake to fail when pool is at capacity and no shard fits")
+	}
+
+	buf1.Done()
+
+	if st := pool.Stats(); st.InUse != 0 || st.Idle != 1 {
+		t.Fatalf("bad stats %+v, want InUse=0 Idle=1", st)
+	}
+}
+
+func TestBoundedMakeContextBlocksAndUnblocks(t *testing.T) {
+	pool := bufpool.NewBounded(100, 1)
+	buf1, ok := pool.TryMake(90)
+	if !ok {
+		t.Fatal("expected first TryMake to succeed")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		buf2, ok := pool.MakeContext(ctx, 20)
+		if !ok {
+			t.Error("expected MakeContext to eventually succeed once a shard is returned")
+			return
+		}
+		buf2.Done()
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("MakeContext returned before a shard was freed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	buf1.Done()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("MakeContext never unblocked after a shard was returned")
+	}
+}
+
+func TestBoundedMakeContextTimesOut(t *testing.T) {
+	pool := bufpool.NewBounded(100, 1)
+	buf1, ok := pool.TryMake(90)
+	if !ok {
+		t.Fatal("expected first TryMake to succeed")
+	}
+	defer buf1.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, ok := pool.MakeContext(ctx, 20); ok {
+		t.Fatal("expected MakeContext to time out while pool is at capacity")
+	}
+}
@@ -3,14 +3,18 @@ package bufpool
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // A Pool holds reusable set of []byte to be arbitrarly subsliced in
 // future requests.
 type Pool struct {
-	sz         int
-	pool       sync.Pool // of *shard
-	maxRetries int
+	sz            int
+	pool          sync.Pool // of *shard
+	maxRetries    int
+	shardTTL      int64 // nanoseconds; 0 disables TTL-based reaping
+	maxIdleShards int
+	debug         debugState
 }
 
 // New returns a new *Pool, where each shard has `sz` bytes size, and
@@ -21,21 +25,101 @@ func New(sz int, opts ...Option) *Pool {
 	for _, o := range opts {
 		o(&cfg)
 	}
-	return &Pool{
-		sz: sz,
-		pool: sync.Pool{
-			New: func() interface{} {
-				return &shard{b: make([]byte, sz)}
-			},
-		},
-		maxRetries: cfg.maxRetries,
+	p := &Pool{
+		sz:            sz,
+		maxRetries:    cfg.maxRetries,
+		shardTTL:      int64(cfg.shardTTL),
+		maxIdleShards: cfg.maxIdleShards,
 	}
+	p.pool.New = func() interface{} {
+		s := &shard{b: make([]byte, sz)}
+		atomic.StoreInt64(&s.returnedAt, time.Now().UnixNano())
+		p.debug.registerShard(s)
+		return s
+	}
+	return p
+}
+
+// Shutdown marks the pool as no longer in use. In normal builds it's a
+// no-op; built with the bufpool_debug tag, it panics if any Buffer ever
+// allocated by this pool is still outstanding (Done hasn't been called
+// on it), which would otherwise silently leak a shard.
+func (p *Pool) Shutdown() {
+	p.debug.checkDrained()
 }
 
 type shard struct {
-	b    []byte
-	refs int64
-	off  int
+	b          []byte
+	refs       int64
+	off        int64 // next free byte offset; accessed atomically
+	returnedAt int64 // unix nanoseconds, set whenever the shard is put back idle
+
+	// lastOff/lastLen describe the most recent allocation carved out of
+	// this shard, so Pool.Append/Grow can try to extend it in place.
+	// Accessed atomically.
+	lastOff int64
+	lastLen int64
+}
+
+// reserve atomically carves out n bytes from the shard's remaining
+// space and records it as the shard's new tail allocation. It reports
+// false if the shard doesn't currently have room.
+func (s *shard) reserve(n int) (start int64, ok bool) {
+	for {
+		off := atomic.LoadInt64(&s.off)
+		end := off + int64(n)
+		if end >= int64(len(s.b)) {
+			return 0, false
+		}
+		if atomic.CompareAndSwapInt64(&s.off, off, end) {
+			atomic.StoreInt64(&s.lastOff, off)
+			atomic.StoreInt64(&s.lastLen, int64(n))
+			return off, true
+		}
+	}
+}
+
+// resetAndReserve rewinds the shard back to its beginning and reserves
+// the first n bytes. It's only safe to call once all previous
+// sub-buffers have been returned (refs == 0).
+func (s *shard) resetAndReserve(n int) (start int64) {
+	atomic.StoreInt64(&s.off, int64(n))
+	atomic.StoreInt64(&s.lastOff, 0)
+	atomic.StoreInt64(&s.lastLen, int64(n))
+	return 0
+}
+
+// growInPlace attempts to extend buf, which must be the shard's current
+// tail allocation, by extra bytes without copying. It reports false if
+// buf isn't (or is no longer) the tail allocation, or if the shard
+// doesn't have extra bytes of room left.
+func (s *shard) growInPlace(buf []byte, extra int) ([]byte, bool) {
+	if extra == 0 {
+		return buf, true
+	}
+	lastOff := atomic.LoadInt64(&s.lastOff)
+	lastLen := atomic.LoadInt64(&s.lastLen)
+	if lastLen != int64(len(buf)) || len(buf) == 0 || &s.b[lastOff] != &buf[0] {
+		return nil, false
+	}
+	off := lastOff + lastLen
+	newOff := off + int64(extra)
+	if newOff > int64(len(s.b)) {
+		return nil, false
+	}
+	if !atomic.CompareAndSwapInt64(&s.off, off, newOff) {
+		// Someone else (a concurrent Make or Grow/Append) has already
+		// allocated past our tail; give up rather than clobber it.
+		return nil, false
+	}
+	// The extended region may hold bytes left over from a since-Done'd
+	// allocation that previously occupied this shard slot; zero it so the
+	// "extra zero bytes" contract holds regardless of shard reuse.
+	for i := off; i < newOff; i++ {
+		s.b[i] = 0
+	}
+	atomic.StoreInt64(&s.lastLen, lastLen+int64(extra))
+	return s.b[lastOff:newOff:newOff], true
 }
 
 // Make returns a Buffer which contains a []byte with lenght/capacity
@@ -47,7 +131,9 @@ func (p *Pool) Make(n int) Buffer {
 	if n == 0 || n >= p.sz {
 		return mallocBuffer(n)
 	}
+	p.reapIdle()
 	var s *shard
+	var start int64
 	for i := 0; i < p.maxRetries && s == nil; i++ {
 		st := p.pool.Get().(*shard)
 		// Return pools after we got the subslice, as to
@@ -59,27 +145,90 @@ func (p *Pool) Make(n int) Buffer {
 		// means those shards where somewhat "full". Of course, depends
 		// on the requested size but there's a reasoanble chance that won't
 		// be useful for other callers. (hand-wavy argument).
-		defer p.pool.Put(st) // better luck next time
+		defer p.putShard(st) // better luck next time
 
-		switch {
-		case st.off+n < len(st.b):
+		if off, ok := st.reserve(n); ok {
 			// Enough bytes left in this shard to satisfy the request.
-			s = st
-		case atomic.LoadInt64(&st.refs) == 0:
+			s, start = st, off
+		} else if atomic.LoadInt64(&st.refs) == 0 {
 			// All old buffers returned; start again at the beginning.
-			s = st
-			s.off = 0
+			s, start = st, st.resetAndReserve(n)
 		}
 	}
 	if s == nil {
 		s = p.pool.New().(*shard)
-		defer p.pool.Put(s)
+		defer p.putShard(s)
+		start = s.resetAndReserve(n)
 	}
 
 	atomic.AddInt64(&s.refs, 1) // incr refcount
-	b := s.b[s.off : s.off+n : s.off+n]
-	s.off += n
-	return Buffer{B: b, refs: &s.refs}
+	end := start + int64(n)
+	b := s.b[start:end:end]
+	debugTrackBuffer(b)
+	return Buffer{B: b, refs: &s.refs, shard: s}
+}
+
+// Append returns a Buffer whose content is b's content followed by
+// extra zero bytes. If b is the most recent allocation from its shard
+// and that shard still has extra bytes of room, this extends b in place
+// by bumping the shard's offset, without copying or taking a new
+// refcount. Otherwise it allocates a new Buffer of the required size,
+// copies b's content into it, and calls Done on b.
+func (p *Pool) Append(b Buffer, extra int) Buffer {
+	if extra < 0 {
+		panic("extra should be greater than or equal to zero")
+	}
+	if b.shard != nil {
+		if grown, ok := b.shard.growInPlace(b.B, extra); ok {
+			return Buffer{B: grown, refs: b.refs, shard: b.shard}
+		}
+	}
+	grown := p.Make(len(b.B) + extra)
+	copy(grown.B, b.B)
+	b.Done()
+	return grown
+}
+
+// Grow returns a Buffer of length n with b's existing content preserved.
+// It's a cheap no-op, returning b unchanged, if n is no larger than
+// len(b.B); otherwise it behaves like Append(b, n-len(b.B)).
+func (p *Pool) Grow(b Buffer, n int) Buffer {
+	if n <= len(b.B) {
+		return b
+	}
+	return p.Append(b, n-len(b.B))
+}
+
+// putShard timestamps a shard and returns it to the underlying sync.Pool.
+func (p *Pool) putShard(s *shard) {
+	atomic.StoreInt64(&s.returnedAt, time.Now().UnixNano())
+	p.pool.Put(s)
+}
+
+// reapIdle opportunistically drops a handful of idle shards that have
+// outlived shardTTL, instead of keeping every shard forever. There's no
+// background goroutine: each Make call inspects up to maxIdleShards
+// shards, dropping expired ones (simply not returning them to the
+// sync.Pool, letting the GC reclaim them) and putting the rest back, so
+// the cost of reaping is amortized across normal traffic.
+func (p *Pool) reapIdle() {
+	if p.shardTTL <= 0 || p.maxIdleShards <= 0 {
+		return
+	}
+	now := time.Now().UnixNano()
+	for i := 0; i < p.maxIdleShards; i++ {
+		st := p.pool.Get().(*shard)
+		if atomic.LoadInt64(&st.refs) != 0 {
+			// Still referenced: not safe to drop, and probing further
+			// would just keep cycling back to this same shard.
+			p.putShard(st)
+			return
+		}
+		if now-atomic.LoadInt64(&st.returnedAt) > p.shardTTL {
+			continue // expired: don't put it back, let the GC reclaim it
+		}
+		p.putShard(st)
+	}
 }
 
 // Buffer is a []byte allocated and managed by a Pool.
@@ -88,8 +237,9 @@ func (p *Pool) Make(n int) Buffer {
 // used as values (Buffer, not *Buffer), to avoid heap
 // allocations.
 type Buffer struct {
-	B    []byte
-	refs *int64
+	B     []byte
+	refs  *int64
+	shard *shard // owning shard, if any; used by Pool.Append/Grow
 }
 
 func mallocBuffer(n int) Buffer {
@@ -117,6 +267,7 @@ func (b *Buffer) Done() {
 	// b.ctr is nil if b's slice was allocated through a call to make,
 	// or if Done has already been called.
 	if b.refs != nil {
+		debugReleaseBuffer(b.B)     // panics on double-release, debug builds only
 		atomic.AddInt64(b.refs, -1) // decr from region refcount
 		b.refs = nil
 	}
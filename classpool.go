@@ -0,0 +1,51 @@
+package bufpool
+
+// A ClassPool manages several size-classed Pools (one Pool per class,
+// geometrically spaced between minSz and maxSz), and routes each Make
+// call to the smallest class that can satisfy it. This avoids the
+// classic sync.Pool pitfall where a single pool holding heterogeneously
+// sized elements ends up pinning a large shard alive just to satisfy a
+// tiny request.
+type ClassPool struct {
+	classes []*Pool // sorted ascending by shard size
+	factor  float64
+}
+
+// NewClassed returns a new *ClassPool with size classes at minSz, 2*minSz,
+// 4*minSz, and so on, the largest being no greater than maxSz. Make(n)
+// routes to the smallest class whose shard size is strictly greater than
+// n*classOverheadFactor (see WithClassOverheadFactor), falling back to an
+// unpooled allocation when no class is that large.
+func NewClassed(minSz, maxSz int, opts ...Option) *ClassPool {
+	if minSz <= 0 || maxSz < minSz {
+		panic("invalid size class range")
+	}
+	cfg := defaultCfg
+	for _, o := range opts {
+		o(&cfg)
+	}
+	var classes []*Pool
+	for sz := minSz; sz <= maxSz; sz *= 2 {
+		classes = append(classes, New(sz, opts...))
+	}
+	return &ClassPool{classes: classes, factor: cfg.classOverheadFactor}
+}
+
+// Make returns a Buffer which contains a []byte with length/capacity
+// equal to `n` bytes, served from the smallest size class that can
+// satisfy the request.
+func (cp *ClassPool) Make(n int) Buffer {
+	if n < 0 {
+		panic("size should be greater than zero")
+	}
+	required := int(float64(n) * cp.factor)
+	for _, p := range cp.classes {
+		// Pool.Make rejects with n >= p.sz (falling back to an unpooled
+		// allocation), so the class must be strictly larger than the
+		// (factor-adjusted) request, not just equal to it.
+		if p.sz > required {
+			return p.Make(n)
+		}
+	}
+	return mallocBuffer(n)
+}
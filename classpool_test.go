@@ -0,0 +1,76 @@
+package bufpool_test
+
+import (
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/jsign/bufpool"
+)
+
+func TestClassedRouting(t *testing.T) {
+	cp := bufpool.NewClassed(64, 16*1024*1024)
+
+	buf := cp.Make(10)
+	if len(buf.B) != 10 {
+		t.Fatalf("bad make len=%v, want 10", len(buf.B))
+	}
+	if cap(buf.B) != 10 {
+		t.Fatalf("bad make cap=%v, want 10", cap(buf.B))
+	}
+	buf.Done()
+}
+
+func TestClassedPoolsExactClassSizeRequests(t *testing.T) {
+	cp := bufpool.NewClassed(64, 1024)
+
+	// A request exactly equal to a class size (64) must still be routed
+	// to a pooled class rather than always falling back to mallocBuffer.
+	// It has to come from a class larger than 64, since Pool.Make itself
+	// only serves requests strictly smaller than its shard size.
+	buf := cp.Make(64)
+	if len(buf.B) != 64 {
+		t.Fatalf("bad make len=%v, want 64", len(buf.B))
+	}
+	base := unsafe.Pointer(&buf.B[0])
+	buf.Done()
+
+	// Once returned, a pooled buffer's shard is rewound and reused, so a
+	// following Make(64) lands at the same address; a bare malloc never
+	// would. sync.Pool may drop a shard on Put (it does so at random under
+	// the race detector), so allow a few rounds.
+	reused := false
+	for i := 0; i < 10 && !reused; i++ {
+		again := cp.Make(64)
+		reused = unsafe.Pointer(&again.B[0]) == base
+		base = unsafe.Pointer(&again.B[0])
+		again.Done()
+	}
+	if !reused {
+		t.Fatal("Make(64) wasn't served from a pooled class, got a bare 64-byte malloc")
+	}
+}
+
+func TestClassedDoesNotPinLargeShard(t *testing.T) {
+	cp := bufpool.NewClassed(64, 16*1024*1024)
+
+	// Force the large class to be created and populated.
+	big := cp.Make(8 * 1024 * 1024)
+	// big is the first allocation of a fresh 16MB-class shard, so it
+	// starts at the shard's first byte.
+	bigStart := uintptr(unsafe.Pointer(&big.B[0]))
+	bigEnd := bigStart + 16*1024*1024
+	big.Done()
+	runtime.GC()
+
+	// A burst of small allocations should never be served out of the
+	// large class, i.e. they must not keep the 16MB shard alive.
+	for i := 0; i < 1000; i++ {
+		small := cp.Make(8)
+		if addr := uintptr(unsafe.Pointer(&small.B[0])); addr >= bigStart && addr < bigEnd {
+			t.Fatalf("small allocation #%d pinned the large 16MB shard", i)
+		}
+		small.Done()
+	}
+	runtime.KeepAlive(big.B) // keep the 16MB shard's address range from being reused
+}
@@ -0,0 +1,86 @@
+//go:build bufpool_debug
+
+package bufpool
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// outstanding tracks the start address of every Buffer handed out by a
+// Make call that hasn't yet been released through Done. Keys are the
+// uintptr of &b[0] rather than an unsafe.Pointer: we only ever use them
+// for equality and range comparisons, never to access memory through
+// them, and bufpool's backing arrays are never moved by the GC.
+var outstanding sync.Map // map[uintptr]struct{}
+
+func bufferKey(b []byte) (uintptr, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	return uintptr(unsafe.Pointer(&b[0])), true
+}
+
+// debugTrackBuffer records a freshly carved-out Buffer as outstanding. It
+// panics if the same address is already tracked, which means a shard was
+// recycled (its offset reset) while a sub-buffer into it was still live.
+func debugTrackBuffer(b []byte) {
+	key, ok := bufferKey(b)
+	if !ok {
+		return
+	}
+	if _, loaded := outstanding.LoadOrStore(key, struct{}{}); loaded {
+		panic(fmt.Sprintf("bufpool: shard recycled at %#x while an outstanding Buffer still referenced it", key))
+	}
+}
+
+// debugReleaseBuffer marks a Buffer as released. It panics if the buffer
+// isn't currently tracked as outstanding, which means Done was already
+// called on it (directly, or through another copy of the same Buffer
+// value).
+func debugReleaseBuffer(b []byte) {
+	key, ok := bufferKey(b)
+	if !ok {
+		return
+	}
+	if _, loaded := outstanding.LoadAndDelete(key); !loaded {
+		panic(fmt.Sprintf("bufpool: double Done() detected on buffer at %#x", key))
+	}
+}
+
+// debugState tracks every shard a Pool has ever created, so Shutdown can
+// verify none of them still has an outstanding Buffer.
+type debugState struct {
+	mu     sync.Mutex
+	shards []*shard
+}
+
+func (d *debugState) registerShard(s *shard) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.shards = append(d.shards, s)
+}
+
+// checkDrained panics if any shard this Pool has ever created still has
+// an outstanding Buffer referencing it.
+func (d *debugState) checkDrained() {
+	d.mu.Lock()
+	shards := append([]*shard(nil), d.shards...)
+	d.mu.Unlock()
+
+	for _, s := range shards {
+		if len(s.b) == 0 {
+			continue
+		}
+		base := uintptr(unsafe.Pointer(&s.b[0]))
+		end := base + uintptr(len(s.b))
+		outstanding.Range(func(key, _ interface{}) bool {
+			k := key.(uintptr)
+			if k >= base && k < end {
+				panic(fmt.Sprintf("bufpool: Shutdown called with an outstanding Buffer at %#x still live in a shard", k))
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,36 @@
+//go:build bufpool_debug
+
+package bufpool
+
+import "testing"
+
+// TestShardRecycledWhileOutstandingPanics white-box tests debugTrackBuffer's
+// half of the debug checks (the other half, debugReleaseBuffer's double-Done
+// check, is covered from the outside in debug_test.go's TestDoubleDonePanics): recycling a
+// shard's offset while a previously carved-out sub-buffer into it is
+// still live and untracked-as-released must panic rather than silently
+// letting the new allocation alias the old one.
+func TestShardRecycledWhileOutstandingPanics(t *testing.T) {
+	s := &shard{b: make([]byte, 20)}
+
+	start, ok := s.reserve(10)
+	if !ok {
+		t.Fatal("expected reserve to succeed on a fresh shard")
+	}
+	outstanding := s.b[start : start+10 : start+10]
+	debugTrackBuffer(outstanding) // as Make would, for the still-live buffer above
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected recycling a shard over a still-outstanding buffer to panic")
+		}
+	}()
+
+	// Simulate Pool.Make wrongly treating the shard as fully returned
+	// (refs==0) and resetting it while `outstanding` is still live: the
+	// reset lands the new allocation at the same address, which
+	// debugTrackBuffer must refuse.
+	newStart := s.resetAndReserve(10)
+	recycled := s.b[newStart : newStart+10 : newStart+10]
+	debugTrackBuffer(recycled)
+}
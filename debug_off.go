@@ -0,0 +1,16 @@
+//go:build !bufpool_debug
+
+package bufpool
+
+// debugState is a no-op stand-in used in normal builds. See debug.go
+// (built with the bufpool_debug tag) for the real implementation that
+// tracks outstanding Buffers and catches double-release bugs.
+type debugState struct{}
+
+func (d *debugState) registerShard(s *shard) {}
+
+func (d *debugState) checkDrained() {}
+
+func debugTrackBuffer(b []byte) {}
+
+func debugReleaseBuffer(b []byte) {}
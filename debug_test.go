@@ -0,0 +1,44 @@
+//go:build bufpool_debug
+
+package bufpool_test
+
+import (
+	"testing"
+
+	"github.com/jsign/bufpool"
+)
+
+func TestDoubleDonePanics(t *testing.T) {
+	pool := bufpool.New(100)
+	buf := pool.Make(10)
+	cp := buf // a copy: Done on one doesn't nil out the other's refs
+
+	buf.Done()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Done on a copy of an already-released Buffer to panic")
+		}
+	}()
+	cp.Done()
+}
+
+func TestShutdownWithOutstandingBufferPanics(t *testing.T) {
+	pool := bufpool.New(100)
+	buf := pool.Make(10)
+	defer buf.Done()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Shutdown to panic while a Buffer is still outstanding")
+		}
+	}()
+	pool.Shutdown()
+}
+
+func TestShutdownAfterDoneIsClean(t *testing.T) {
+	pool := bufpool.New(100)
+	buf := pool.Make(10)
+	buf.Done()
+	pool.Shutdown() // must not panic
+}
@@ -0,0 +1,137 @@
+package bufpool_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/jsign/bufpool"
+)
+
+func TestAppendExtendsInPlace(t *testing.T) {
+	pool := bufpool.New(100)
+
+	buf := pool.Make(10)
+	copy(buf.B, "0123456789")
+	base := unsafe.Pointer(&buf.B[0])
+
+	buf = pool.Append(buf, 5)
+	if len(buf.B) != 15 {
+		t.Fatalf("bad len=%v, want 15", len(buf.B))
+	}
+	if unsafe.Pointer(&buf.B[0]) != base {
+		t.Fatal("expected Append to extend the buffer in place, not copy it")
+	}
+	if string(buf.B[:10]) != "0123456789" {
+		t.Fatalf("original content not preserved: %q", buf.B[:10])
+	}
+	buf.Done()
+}
+
+func TestAppendFallsBackToCopyWhenNotTail(t *testing.T) {
+	pool := bufpool.New(100)
+
+	buf1 := pool.Make(10)
+	copy(buf1.B, "0123456789")
+	base1 := unsafe.Pointer(&buf1.B[0])
+
+	// buf2 becomes the shard's new tail allocation, so extending buf1 in
+	// place is no longer safe. This relies on sync.Pool hanging onto the
+	// single idle shard between the two Make calls; skip if a GC raced
+	// us and cleared it, landing buf2 in a fresh shard instead.
+	buf2 := pool.Make(5)
+	if uintptr(unsafe.Pointer(&buf2.B[0])) != uintptr(base1)+10 {
+		buf1.Done()
+		buf2.Done()
+		t.Skip("buf2 didn't land right after buf1 in the same shard; nothing to assert")
+	}
+
+	buf1 = pool.Append(buf1, 5)
+	if len(buf1.B) != 15 {
+		t.Fatalf("bad len=%v, want 15", len(buf1.B))
+	}
+	if unsafe.Pointer(&buf1.B[0]) == base1 {
+		t.Fatal("expected Append to copy into a new buffer once buf1 is no longer the tail")
+	}
+	if string(buf1.B[:10]) != "0123456789" {
+		t.Fatalf("original content not preserved: %q", buf1.B[:10])
+	}
+
+	buf1.Done()
+	buf2.Done()
+}
+
+func TestAppendFallsBackWhenShardIsFull(t *testing.T) {
+	pool := bufpool.New(20)
+
+	buf := pool.Make(15)
+	copy(buf.B, "0123456789abcde")
+	base := unsafe.Pointer(&buf.B[0])
+
+	buf = pool.Append(buf, 10) // doesn't fit in the 20-byte shard
+	if len(buf.B) != 25 {
+		t.Fatalf("bad len=%v, want 25", len(buf.B))
+	}
+	if unsafe.Pointer(&buf.B[0]) == base {
+		t.Fatal("expected Append to copy into a new buffer when the shard has no room left")
+	}
+	if string(buf.B[:15]) != "0123456789abcde" {
+		t.Fatalf("original content not preserved: %q", buf.B[:15])
+	}
+	buf.Done()
+}
+
+func TestAppendZeroesRecycledShardBytes(t *testing.T) {
+	pool := bufpool.New(20)
+
+	// Dirty the whole shard, then return it so the next Make resets and
+	// reuses it from offset 0.
+	dirty := pool.Make(19)
+	for i := range dirty.B {
+		dirty.B[i] = 0xFF
+	}
+	dirtyBase := unsafe.Pointer(&dirty.B[0])
+	dirty.Done()
+
+	// buf should land at the start of the (dirty) recycled shard. This
+	// relies on sync.Pool hanging onto the single idle shard; skip if a
+	// GC raced us and cleared it, landing buf in a fresh, clean shard
+	// instead, which would make the rest of this test vacuous.
+	buf := pool.Make(5)
+	if unsafe.Pointer(&buf.B[0]) != dirtyBase {
+		buf.Done()
+		t.Skip("buf didn't land in the recycled dirty shard; nothing to assert")
+	}
+
+	// The bytes Append exposes past buf's tail are leftovers from the
+	// dirty allocation above; they must come back zeroed, not as 0xFF,
+	// per Append's documented contract.
+	buf = pool.Append(buf, 10)
+	for i, c := range buf.B[5:] {
+		if c != 0 {
+			t.Fatalf("byte %d of the grown tail is %#x, want 0 (leaked recycled shard data)", i, c)
+		}
+	}
+	buf.Done()
+}
+
+func TestGrow(t *testing.T) {
+	pool := bufpool.New(100)
+
+	buf := pool.Make(10)
+	copy(buf.B, "0123456789")
+
+	// Shrinking or keeping the same size is a no-op.
+	same := pool.Grow(buf, 5)
+	if unsafe.Pointer(&same.B[0]) != unsafe.Pointer(&buf.B[0]) || len(same.B) != 10 {
+		t.Fatalf("expected Grow to no-op when n <= len(b.B), got len=%v", len(same.B))
+	}
+
+	grown := pool.Grow(buf, 20)
+	if len(grown.B) != 20 {
+		t.Fatalf("bad len=%v, want 20", len(grown.B))
+	}
+	if string(grown.B[:10]) != "0123456789" {
+		t.Fatalf("original content not preserved: %q", grown.B[:10])
+	}
+	grown.Done()
+}
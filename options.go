@@ -1,13 +1,18 @@
 package bufpool
 
+import "time"
+
 type cfg struct {
-	maxRetries int
+	maxRetries          int
+	classOverheadFactor float64
+	shardTTL            time.Duration
+	maxIdleShards       int
 }
 
 // Option modifies the default configuration.
 type Option func(*cfg)
 
-var defaultCfg = cfg{maxRetries: 1}
+var defaultCfg = cfg{maxRetries: 1, classOverheadFactor: 1}
 
 // WithMaxRetries sets the maximum number of retries
 // finding a suitable shard from the pool before creating
@@ -17,3 +22,40 @@ func WithMaxRetries(max int) Option {
 		cfg.maxRetries = max
 	}
 }
+
+// WithClassOverheadFactor sets the multiplier applied to a requested size
+// when a ClassPool decides which size class to route to. A factor above 1
+// reserves some headroom in the chosen class (e.g. to accommodate a
+// subsequent Grow/Append without switching classes); the default is 1,
+// meaning the smallest class strictly larger than the request is used.
+// It has no effect on a plain Pool created with New.
+func WithClassOverheadFactor(factor float64) Option {
+	return func(cfg *cfg) {
+		cfg.classOverheadFactor = factor
+	}
+}
+
+// WithShardTTL sets how long a shard may sit idle in a Pool before it
+// becomes eligible for reaping on a future Make call. The zero value (the
+// default) disables TTL-based reaping, matching the pool's original
+// behavior of keeping shards around indefinitely. Has no effect unless
+// WithMaxIdleShards is also set to a positive value, and no effect at all
+// on a BoundedPool created with NewBounded.
+func WithShardTTL(d time.Duration) Option {
+	return func(cfg *cfg) {
+		cfg.shardTTL = d
+	}
+}
+
+// WithMaxIdleShards bounds how many idle shards a single Make call will
+// opportunistically inspect (and reap, if expired per WithShardTTL) before
+// proceeding with the caller's actual request. This amortizes the cost of
+// TTL enforcement across normal traffic instead of scanning the whole
+// pool at once, which sync.Pool doesn't support anyway. Zero (the
+// default) disables reaping even if WithShardTTL is set. Has no effect
+// on a BoundedPool created with NewBounded.
+func WithMaxIdleShards(n int) Option {
+	return func(cfg *cfg) {
+		cfg.maxIdleShards = n
+	}
+}
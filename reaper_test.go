@@ -0,0 +1,34 @@
+package bufpool_test
+
+import (
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/jsign/bufpool"
+)
+
+func TestShardTTLReaping(t *testing.T) {
+	ttl := 10 * time.Millisecond
+	pool := bufpool.New(100, bufpool.WithShardTTL(ttl), bufpool.WithMaxIdleShards(2))
+
+	// Use up nearly the whole shard so the next allocation can't just be
+	// carved out of its remaining space and must go through the
+	// off-reset-or-reap decision below.
+	buf1 := pool.Make(95)
+	ptr1 := unsafe.Pointer(&buf1.B[0])
+	buf1.Done()
+
+	time.Sleep(ttl * 5)
+
+	// This call's internal reaping pass should find the expired shard idle
+	// in the pool and drop it, so the request below gets served by a
+	// freshly allocated shard instead.
+	buf2 := pool.Make(10)
+	ptr2 := unsafe.Pointer(&buf2.B[0])
+	buf2.Done()
+
+	if ptr1 == ptr2 {
+		t.Fatal("expected the expired shard to have been reaped and replaced by a fresh one")
+	}
+}